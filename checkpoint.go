@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	checkpointFilePrefix = "checkpoint-"
+	checkpointFileSuffix = ".json"
+)
+
+// CheckpointConfig controls periodic checkpointing of accumulated results. An
+// empty ArchiveRoot disables checkpointing and restore entirely.
+type CheckpointConfig struct {
+	ArchiveRoot string
+	Interval    time.Duration
+}
+
+// checkpointFile is the on-disk shape of a checkpoint: the kept results plus
+// every input row's key that was attempted (kept or not), so a restore can
+// skip all already-attempted rows rather than only the kept ones.
+type checkpointFile struct {
+	Computed  []ComputedStructure `json:"computed"`
+	Processed []string            `json:"processed"`
+}
+
+// SaveCheckpoint serializes data and the keys in processed to a timestamped
+// JSON file under archiveRoot, creating the directory if needed. It writes to
+// a temp file first and renames it into place so a crash mid-write can't
+// leave a truncated checkpoint behind.
+func SaveCheckpoint(archiveRoot string, data []ComputedStructure, processed map[string]struct{}) error {
+	if err := os.MkdirAll(archiveRoot, 0o755); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(processed))
+	for key := range processed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fileName := fmt.Sprintf("%s%d%s", checkpointFilePrefix, time.Now().UnixNano(), checkpointFileSuffix)
+	path := filepath.Join(archiveRoot, fileName)
+	tmpPath := path + ".tmp"
+
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	payload := checkpointFile{Computed: data, Processed: keys}
+	if err := json.NewEncoder(file).Encode(payload); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// LoadLatestCheckpoint finds the newest checkpoint file under archiveRoot and
+// returns its kept results and the set of processed row keys. If archiveRoot
+// doesn't exist or contains no checkpoints, it returns a nil slice, an empty
+// set and no error.
+func LoadLatestCheckpoint(archiveRoot string) ([]ComputedStructure, map[string]struct{}, error) {
+	entries, err := os.ReadDir(archiveRoot)
+	if os.IsNotExist(err) {
+		return nil, make(map[string]struct{}), nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var newest string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, checkpointFilePrefix) || !strings.HasSuffix(name, checkpointFileSuffix) {
+			continue
+		}
+		if name > newest {
+			newest = name
+		}
+	}
+	if newest == "" {
+		return nil, make(map[string]struct{}), nil
+	}
+
+	file, err := os.Open(filepath.Join(archiveRoot, newest))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var payload checkpointFile
+	if err := json.NewDecoder(file).Decode(&payload); err != nil {
+		return nil, nil, err
+	}
+
+	processed := make(map[string]struct{}, len(payload.Processed))
+	for _, key := range payload.Processed {
+		processed[key] = struct{}{}
+	}
+	return payload.Computed, processed, nil
+}
+
+// checkpointKey returns the dedupe key used to skip input rows already
+// attempted in a restored checkpoint.
+func checkpointKey(s Structure) string {
+	return fmt.Sprintf("%s|%.6f|%.6f|%d", s.Date, s.Lat, s.Lng, s.Hour)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSaveAndLoadCheckpointRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	data := []ComputedStructure{
+		{Date: "2026-03-20", Lat: 10, Lng: 20, Hour: 18, SunsetHour: 18},
+		{Date: "2026-03-21", Lat: -5, Lng: 30, Hour: 19, SunsetHour: 19},
+	}
+	processed := map[string]struct{}{
+		checkpointKey(Structure{Date: "2026-03-20", Lat: 10, Lng: 20, Hour: 18}): {},
+		checkpointKey(Structure{Date: "2026-03-21", Lat: -5, Lng: 30, Hour: 19}): {},
+		checkpointKey(Structure{Date: "2026-03-22", Lat: 0, Lng: 0, Hour: 12}):   {},
+	}
+
+	if err := SaveCheckpoint(dir, data, processed); err != nil {
+		t.Fatalf("SaveCheckpoint returned error: %v", err)
+	}
+
+	gotData, gotProcessed, err := LoadLatestCheckpoint(dir)
+	if err != nil {
+		t.Fatalf("LoadLatestCheckpoint returned error: %v", err)
+	}
+
+	sortComputed(gotData)
+	sortComputed(data)
+	if !reflect.DeepEqual(gotData, data) {
+		t.Errorf("LoadLatestCheckpoint data = %+v, want %+v", gotData, data)
+	}
+	if !reflect.DeepEqual(gotProcessed, processed) {
+		t.Errorf("LoadLatestCheckpoint processed = %+v, want %+v", gotProcessed, processed)
+	}
+}
+
+func TestLoadLatestCheckpointMissingDir(t *testing.T) {
+	dir := t.TempDir() + "/does-not-exist"
+
+	data, processed, err := LoadLatestCheckpoint(dir)
+	if err != nil {
+		t.Fatalf("LoadLatestCheckpoint returned error: %v", err)
+	}
+	if len(data) != 0 {
+		t.Errorf("data = %+v, want empty", data)
+	}
+	if len(processed) != 0 {
+		t.Errorf("processed = %+v, want empty", processed)
+	}
+}
+
+func sortComputed(data []ComputedStructure) {
+	sort.Slice(data, func(i, j int) bool {
+		return data[i].Hour < data[j].Hour
+	})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// HTTPProviderConfig controls the HTTP sunset provider's connection pooling,
+// rate limiting and retry behavior.
+type HTTPProviderConfig struct {
+	QPS                 float64 `json:"qps"`
+	MaxRetries          int     `json:"max_retries"`
+	BaseBackoffMS       int     `json:"base_backoff_ms"`
+	MaxIdleConnsPerHost int     `json:"max_idle_conns_per_host"`
+}
+
+// BaseBackoff returns BaseBackoffMS as a time.Duration.
+func (c HTTPProviderConfig) BaseBackoff() time.Duration {
+	return time.Duration(c.BaseBackoffMS) * time.Millisecond
+}
+
+// DefaultHTTPProviderConfig returns the provider's defaults, used when no
+// -sunset-config file is given or a field is left zero-valued in one.
+func DefaultHTTPProviderConfig() HTTPProviderConfig {
+	return HTTPProviderConfig{
+		QPS:                 5,
+		MaxRetries:          3,
+		BaseBackoffMS:       200,
+		MaxIdleConnsPerHost: 10,
+	}
+}
+
+// LoadHTTPProviderConfig starts from DefaultHTTPProviderConfig and overrides
+// it with any fields set in the JSON file at path. An empty path returns the
+// defaults unchanged.
+func LoadHTTPProviderConfig(path string) (HTTPProviderConfig, error) {
+	cfg := DefaultHTTPProviderConfig()
+	if path == "" {
+		return cfg, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return cfg, err
+	}
+	defer file.Close()
+
+	if err := json.NewDecoder(file).Decode(&cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
@@ -1,15 +1,28 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
+	"os/signal"
+	"runtime"
 	"sort"
+	"sync"
+	"syscall"
 	"time"
 )
 
+var (
+	archiveRoot        = flag.String("archive-root", "", "directory to store/restore result checkpoints (disabled if empty)")
+	checkpointInterval = flag.Duration("checkpoint-interval", 30*time.Second, "how often to checkpoint accumulated results")
+	sunsetProviderName = flag.String("sunset-provider", "http", `sunset hour provider: "http" (api.sunrise-sunset.org) or "local" (offline NOAA solar calculator)`)
+	sunsetConfigPath   = flag.String("sunset-config", "", "path to JSON file overriding HTTP sunset provider settings (qps, max_retries, base_backoff_ms, max_idle_conns_per_host)")
+	sunsetQPS          = flag.Float64("sunset-qps", 0, "requests/sec ceiling for the HTTP sunset provider (0 keeps the -sunset-config/default value)")
+)
+
 // ------------------------------------------------------- Universal Types
 
 type Structure struct {
@@ -31,18 +44,32 @@ type ComputedStructure struct {
 
 func main() {
 	log.SetFlags(0)
-	if len(os.Args) < 2 {
-		log.Fatalf("Usage: go run main.go <input file path>")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		log.Fatalf("Usage: go run main.go [flags] <input file path>")
 	}
 
-	inputFilePath := os.Args[1]
+	inputFilePath := flag.Arg(0)
 	outputFilePath := "./IFF1-5_BradauskasA_L1_rez.txt"
 	workerCount := 10
+	checkpoint := CheckpointConfig{ArchiveRoot: *archiveRoot, Interval: *checkpointInterval}
+
+	httpProviderConfig, err := LoadHTTPProviderConfig(*sunsetConfigPath)
+	if err != nil {
+		log.Fatalf("Error loading sunset config: %v", err)
+	}
+	if *sunsetQPS > 0 {
+		httpProviderConfig.QPS = *sunsetQPS
+	}
+	provider := NewCachingSunsetProvider(buildSunsetProvider(*sunsetProviderName, httpProviderConfig))
+
+	fmt.Println("Starting metrics server on", metricsAddr)
+	StartMetricsServer(metricsAddr)
 
 	// Start timer
 	startTime := time.Now()
 
-	initializePipeline(inputFilePath, outputFilePath, workerCount)
+	initializePipeline(inputFilePath, outputFilePath, workerCount, checkpoint, provider)
 
 	// Calculate elapsed time
 	elapsedTime := time.Since(startTime)
@@ -51,40 +78,44 @@ func main() {
 	fmt.Println("Application has stopped running")
 }
 
-func initializePipeline(inputFilePath, outputFilePath string, workerCount int) {
-	dataMonitorSendDataChannel := make(chan Structure)
-	dataMonitorRequestDataChannel := make(chan bool, workerCount)
-	dataMonitorResponseChannel := make(chan Structure)
-	noMoreDataChannel := make(chan bool)
-
-	resultMonitorSendDataChannel := make(chan ComputedStructure)
-	resultMonitorSendFinalDataChannel := make(chan []ComputedStructure)
-	workerStatusChannel := make(chan bool)
-
+func initializePipeline(inputFilePath, outputFilePath string, workerCount int, checkpoint CheckpointConfig, provider SunsetProvider) {
 	data, err := ReadJSON(inputFilePath)
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
 
-	fmt.Println("Starting Data Monitor")
-	go InitDataMonitor(dataMonitorSendDataChannel, dataMonitorRequestDataChannel, dataMonitorResponseChannel, noMoreDataChannel)
-
-	fmt.Println("Starting Result Monitor")
-	go InitResultMonitor(resultMonitorSendDataChannel, resultMonitorSendFinalDataChannel, workerCount, workerStatusChannel)
-
-	fmt.Println("Starting Workers Count:", workerCount)
-	for i := 1; i <= workerCount; i++ {
-		fmt.Println("Creating Worker:", i)
-		go InitWorker(dataMonitorRequestDataChannel, dataMonitorResponseChannel, resultMonitorSendDataChannel, workerStatusChannel)
+	var restored []ComputedStructure
+	processed := make(map[string]struct{})
+	if checkpoint.ArchiveRoot != "" {
+		restored, processed, err = LoadLatestCheckpoint(checkpoint.ArchiveRoot)
+		if err != nil {
+			log.Fatalf("Error loading checkpoint: %v", err)
+		}
+		if len(processed) > 0 {
+			fmt.Printf("Restored %d results (%d rows already attempted) from checkpoint\n", len(restored), len(processed))
+		}
 	}
 
+	fmt.Println("Starting Worker Pool, size:", workerCount)
+	pool := NewPool(context.Background(), workerCount, workerCount*2)
+	pool.Run(InitWorker(provider))
+
 	fmt.Println("Inserting Data")
-	for _, value := range data {
-		dataMonitorSendDataChannel <- value
-	}
-	noMoreDataChannel <- true
+	go func() {
+		for _, value := range data {
+			if _, ok := processed[checkpointKey(value)]; ok {
+				continue
+			}
+			pool.Add(value)
+		}
+		pool.CloseInput()
+	}()
 
-	computedData := <-resultMonitorSendFinalDataChannel
+	computedData := InitResultMonitor(pool.Outcomes(), checkpoint, processed, pool.Stop)
+	computedData = append(restored, computedData...)
+	sort.Slice(computedData, func(i, j int) bool {
+		return computedData[i].Hour < computedData[j].Hour
+	})
 
 	writeOutputToFile(outputFilePath, computedData)
 	fmt.Println("Application has stopped running")
@@ -121,118 +152,203 @@ func ReadJSON(filePath string) ([]Structure, error) {
 	return inputData, nil
 }
 
-// InitDataMonitor handles the data insertion and retrieval for workers
-func InitDataMonitor(sendDataChannel chan Structure, requestDataChannel chan bool, responseDataChannel chan Structure, noMoreDataChannel chan bool) {
-	// Simplified the data array to use slice
-	var data []Structure
-	noMoreData := false
-
-	for {
-		select {
-		case insertObject := <-sendDataChannel:
-			data = append(data, insertObject)
-
-		case <-requestDataChannel:
-			if len(data) > 0 {
-				// Retrieve the first element and update the slice
-				responseDataChannel <- data[0]
-				data = data[1:]
-			}
+// ------------------------------------------------------- Worker Pool
+
+// WorkFunc processes a single job, returning the computed result and whether
+// it should be kept, e.g. because it matched the requested hour.
+type WorkFunc func(ctx context.Context, job Structure) (ComputedStructure, bool, error)
+
+// JobOutcome reports a job that a WorkFunc resolved without error, alongside
+// its originating Structure. Keep distinguishes a match worth keeping in the
+// final output from a job that was merely attempted and ruled out — both are
+// "done" for checkpoint/dedup purposes, since only an error leaves a job
+// eligible for retry on restart.
+type JobOutcome struct {
+	Job    Structure
+	Result ComputedStructure
+	Keep   bool
+}
 
-		case <-noMoreDataChannel:
-			noMoreData = true
+// Pool is a bounded worker pool that pulls Structure jobs off a buffered
+// channel and emits JobOutcomes, modeled after stefantalpalaru/pool but
+// scoped to this pipeline's job/result types. Cancellation and deadlines are
+// driven by context.Context instead of sentinel channels. Submitted/running/
+// completed job counts are tracked as Prometheus metrics (see metrics.go)
+// rather than duplicated here.
+type Pool struct {
+	size     int
+	jobs     chan Structure
+	outcomes chan JobOutcome
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
 
-		default:
-			if noMoreData && len(data) == 0 {
-				close(responseDataChannel)
-				return
-			}
-		}
+// NewPool creates a Pool with the given worker count and job buffer size. If
+// size <= 0 it defaults to runtime.NumCPU().
+func NewPool(parentCtx context.Context, size, bufferSize int) *Pool {
+	if size <= 0 {
+		size = runtime.NumCPU()
+	}
+	ctx, cancel := context.WithCancel(parentCtx)
+	return &Pool{
+		size:     size,
+		jobs:     make(chan Structure, bufferSize),
+		outcomes: make(chan JobOutcome, bufferSize),
+		ctx:      ctx,
+		cancel:   cancel,
 	}
 }
 
-// InitResultMonitor handles the accumulation and sorting of results
-func InitResultMonitor(resultMonitorSendDataChannel chan ComputedStructure, resultMonitorSendFinalDataChannel chan []ComputedStructure, workerCount int, workerStatusChannel chan bool) {
-	var data []ComputedStructure
+// Add submits a job to the pool, blocking if the job buffer is full.
+func (p *Pool) Add(job Structure) {
+	p.jobs <- job
+	queueDepth.Set(float64(len(p.jobs)))
+}
+
+// CloseInput signals that no more jobs will be submitted. Workers drain the
+// remaining buffer and exit once it's empty.
+func (p *Pool) CloseInput() {
+	close(p.jobs)
+}
+
+// Run starts the pool's workers. Each pulls jobs from the input channel,
+// applying work, until the channel is closed or the pool's context is
+// cancelled. Outcomes() is closed once every worker has exited.
+func (p *Pool) Run(work WorkFunc) {
+	for i := 0; i < p.size; i++ {
+		p.wg.Add(1)
+		go p.worker(work)
+	}
+	go func() {
+		p.wg.Wait()
+		close(p.outcomes)
+	}()
+}
 
-	for workerCount > 0 {
+func (p *Pool) worker(work WorkFunc) {
+	defer p.wg.Done()
+	for {
 		select {
-		case resultData := <-resultMonitorSendDataChannel:
-			data = append(data, resultData)
-			sort.Slice(data, func(i, j int) bool {
-				return data[i].Hour < data[j].Hour
-			})
-
-		case <-workerStatusChannel:
-			workerCount--
+		case <-p.ctx.Done():
+			return
+		case job, ok := <-p.jobs:
+			if !ok {
+				return
+			}
+			queueDepth.Set(float64(len(p.jobs)))
+			activeWorkers.Inc()
+			result, keep, err := work(p.ctx, job)
+			activeWorkers.Dec()
+			if err != nil {
+				fmt.Printf("Error finding sunset hour: %v\n", err)
+				continue
+			}
+			jobsProcessedTotal.Inc()
+			p.outcomes <- JobOutcome{Job: job, Result: result, Keep: keep}
 		}
 	}
+}
 
-	resultMonitorSendFinalDataChannel <- data
+// Outcomes returns the channel of resolved jobs (both kept and ruled-out),
+// closed once the pool has stopped and every worker has exited. A job that
+// errored is never reported here, leaving it eligible for retry on restart.
+func (p *Pool) Outcomes() <-chan JobOutcome {
+	return p.outcomes
 }
 
-// InitWorker represents a single worker fetching and processing data
-func InitWorker(requestDataChannel chan bool, responseDataChannel chan Structure, resultMonitorSendDataChannel chan ComputedStructure, workerStatusChannel chan bool) {
-	for {
-		requestDataChannel <- true
-		data, more := <-responseDataChannel
-		if !more {
-			break
-		}
+// Stop cancels the pool's context, causing workers to exit without draining
+// the remaining job buffer. Outcomes() closes shortly after, once every
+// worker has observed the cancellation.
+func (p *Pool) Stop() {
+	p.cancel()
+}
 
-		sunsetHour, err := FindSunsetHour(data)
-		if err != nil {
-			fmt.Printf("Error finding sunset hour: %v\n", err)
-			continue
-		}
+// ------------------------------------------------------- Result Monitor
+
+// InitResultMonitor accumulates kept results from the pool, keeping them
+// sorted by Hour, while tracking every resolved job (kept or not) in
+// processed so a restart can skip rows that were already attempted rather
+// than only the rare ones that matched. If checkpoint.ArchiveRoot is set, it
+// periodically checkpoints both to disk. On SIGINT/SIGTERM it saves a final
+// checkpoint and calls stop (typically the pool's Stop) to cancel outstanding
+// work, then keeps draining whatever outcomes are already buffered — this is
+// a deliberate, successful shutdown, not a failure, so it's signalled by
+// returning normally rather than calling os.Exit. processed is mutated in
+// place and also returned via the checkpoint; it must not be read
+// concurrently by the caller. It returns the final slice once the outcomes
+// channel is closed.
+func InitResultMonitor(outcomes <-chan JobOutcome, checkpoint CheckpointConfig, processed map[string]struct{}, stop func()) []ComputedStructure {
+	var data []ComputedStructure
 
-		if sunsetHour != data.Hour {
-			continue
-		}
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
 
-		resultMonitorSendDataChannel <- ComputedStructure{
-			Date:       data.Date,
-			Lat:        data.Lat,
-			Lng:        data.Lng,
-			Hour:       data.Hour,
-			SunsetHour: sunsetHour,
-		}
+	var tickerChan <-chan time.Time
+	if checkpoint.ArchiveRoot != "" && checkpoint.Interval > 0 {
+		ticker := time.NewTicker(checkpoint.Interval)
+		defer ticker.Stop()
+		tickerChan = ticker.C
 	}
 
-	workerStatusChannel <- true
-}
-
-// FindSunsetHour makes an API call to get the sunset time for a given location and date
-func FindSunsetHour(data Structure) (int, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
-	apiURL := fmt.Sprintf("https://api.sunrise-sunset.org/json?lat=%f&lng=%f&date=%s", data.Lat, data.Lng, data.Date)
+	for {
+		select {
+		case outcome, ok := <-outcomes:
+			if !ok {
+				if checkpoint.ArchiveRoot != "" {
+					if err := SaveCheckpoint(checkpoint.ArchiveRoot, data, processed); err != nil {
+						fmt.Printf("Error writing final checkpoint: %v\n", err)
+					}
+				}
+				return data
+			}
+			processed[checkpointKey(outcome.Job)] = struct{}{}
+			if outcome.Keep {
+				data = append(data, outcome.Result)
+				sort.Slice(data, func(i, j int) bool {
+					return data[i].Hour < data[j].Hour
+				})
+			}
 
-	resp, err := client.Get(apiURL)
-	if err != nil {
-		return -1, err
-	}
-	defer resp.Body.Close()
+		case <-tickerChan:
+			if err := SaveCheckpoint(checkpoint.ArchiveRoot, data, processed); err != nil {
+				fmt.Printf("Error writing checkpoint: %v\n", err)
+			}
 
-	var result apiResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return -1, err
+		case <-sigChan:
+			if checkpoint.ArchiveRoot != "" {
+				if err := SaveCheckpoint(checkpoint.ArchiveRoot, data, processed); err != nil {
+					fmt.Printf("Error writing checkpoint on signal: %v\n", err)
+				}
+			}
+			stop()
+		}
 	}
+}
 
-	if result.Status != "OK" {
-		return -1, fmt.Errorf("api returned status: %s", result.Status)
-	}
+// ------------------------------------------------------- Worker
 
-	sunsetTime, err := time.Parse("3:04:05 PM", result.Results.Sunset)
-	if err != nil {
-		return -1, err
-	}
+// InitWorker builds the WorkFunc run by the pool: it resolves each job's
+// sunset hour via provider, keeping only jobs whose sunset hour matches the
+// requested Hour.
+func InitWorker(provider SunsetProvider) WorkFunc {
+	return func(ctx context.Context, job Structure) (ComputedStructure, bool, error) {
+		sunsetHour, err := provider.FindSunsetHour(job)
+		if err != nil {
+			return ComputedStructure{}, false, err
+		}
 
-	return sunsetTime.Hour(), nil
-}
+		if sunsetHour != job.Hour {
+			return ComputedStructure{}, false, nil
+		}
 
-type apiResponse struct {
-	Results struct {
-		Sunset string `json:"sunset"`
-	} `json:"results"`
-	Status string `json:"status"`
+		return ComputedStructure{
+			Date:       job.Date,
+			Lat:        job.Lat,
+			Lng:        job.Lng,
+			Hour:       job.Hour,
+			SunsetHour: sunsetHour,
+		}, true, nil
+	}
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsAddr is the listen address for the embedded Prometheus metrics
+// server.
+const metricsAddr = ":2112"
+
+var (
+	jobsProcessedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sunset_jobs_processed_total",
+		Help: "Total number of Structure jobs processed by the worker pool.",
+	})
+
+	apiErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "sunset_api_errors_total",
+		Help: "Total number of sunrise-sunset.org API responses with a non-OK status, by status.",
+	}, []string{"status"})
+
+	sunsetRequestDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "sunset_request_duration_seconds",
+		Help:    "Latency of FindSunsetHour API requests.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	queueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "sunset_queue_depth",
+		Help: "Current number of jobs buffered in the worker pool's input channel.",
+	})
+
+	activeWorkers = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "sunset_active_workers",
+		Help: "Number of workers currently processing a job.",
+	})
+)
+
+// StartMetricsServer starts an HTTP server exposing Prometheus metrics at
+// /metrics on addr. It runs in the background for the lifetime of the
+// process; a failure to bind or serve is logged rather than fatal, since
+// metrics are not required for the pipeline to produce output.
+func StartMetricsServer(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("metrics server stopped: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolDrainsAllJobsThenClosesOutcomes(t *testing.T) {
+	pool := NewPool(context.Background(), 4, 8)
+
+	var processed int64
+	pool.Run(func(ctx context.Context, job Structure) (ComputedStructure, bool, error) {
+		atomic.AddInt64(&processed, 1)
+		return ComputedStructure{Hour: job.Hour, SunsetHour: job.Hour}, true, nil
+	})
+
+	const jobCount = 20
+	go func() {
+		for i := 0; i < jobCount; i++ {
+			pool.Add(Structure{Hour: i})
+		}
+		pool.CloseInput()
+	}()
+
+	var got int
+	for range pool.Outcomes() {
+		got++
+	}
+
+	if got != jobCount {
+		t.Fatalf("got %d outcomes, want %d", got, jobCount)
+	}
+	if n := atomic.LoadInt64(&processed); n != jobCount {
+		t.Fatalf("work func ran %d times, want %d", n, jobCount)
+	}
+}
+
+func TestPoolStopCancelsBeforeOutcomesClose(t *testing.T) {
+	pool := NewPool(context.Background(), 1, 1)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	pool.Run(func(ctx context.Context, job Structure) (ComputedStructure, bool, error) {
+		close(started)
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return ComputedStructure{}, false, ctx.Err()
+	})
+
+	pool.Add(Structure{Hour: 1})
+	<-started
+
+	pool.Stop()
+
+	select {
+	case _, ok := <-pool.Outcomes():
+		if ok {
+			t.Fatalf("expected Outcomes() to be closed without further values after Stop()")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Outcomes() did not close after Stop()")
+	}
+
+	close(release)
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+// SunsetProvider resolves the sunset hour for a given Structure job. It's the
+// extension point that lets the pipeline swap a networked lookup for an
+// offline computation without touching the worker pool.
+type SunsetProvider interface {
+	FindSunsetHour(data Structure) (int, error)
+}
+
+// buildSunsetProvider constructs the base provider named by the
+// -sunset-provider flag. Unrecognized names fall back to "http". cfg is
+// ignored by providers that don't make network calls.
+func buildSunsetProvider(name string, cfg HTTPProviderConfig) SunsetProvider {
+	switch name {
+	case "local":
+		return NewLocalSunsetProvider()
+	default:
+		return NewHTTPSunsetProvider(cfg)
+	}
+}
+
+// ------------------------------------------------------- HTTP Provider
+
+// HTTPSunsetProvider resolves sunset hours via api.sunrise-sunset.org. It
+// rate-limits outgoing requests and retries transient failures with
+// exponential backoff and jitter.
+type HTTPSunsetProvider struct {
+	client      *http.Client
+	limiter     *rate.Limiter
+	maxRetries  int
+	baseBackoff time.Duration
+}
+
+// NewHTTPSunsetProvider creates an HTTPSunsetProvider tuned by cfg: a shared
+// client with per-host connection pooling and keepalives, throttled to
+// cfg.QPS requests/sec.
+func NewHTTPSunsetProvider(cfg HTTPProviderConfig) *HTTPSunsetProvider {
+	transport := &http.Transport{
+		MaxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	burst := int(cfg.QPS)
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &HTTPSunsetProvider{
+		client:      &http.Client{Timeout: 10 * time.Second, Transport: transport},
+		limiter:     rate.NewLimiter(rate.Limit(cfg.QPS), burst),
+		maxRetries:  cfg.MaxRetries,
+		baseBackoff: cfg.BaseBackoff(),
+	}
+}
+
+// FindSunsetHour makes an API call to get the sunset time for a given
+// location and date, retrying transient failures with exponential backoff
+// and jitter.
+func (p *HTTPSunsetProvider) FindSunsetHour(data Structure) (int, error) {
+	timer := prometheus.NewTimer(sunsetRequestDuration)
+	defer timer.ObserveDuration()
+
+	var lastErr error
+	for attempt := 0; attempt <= p.maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoffWithJitter(p.baseBackoff, attempt))
+		}
+
+		if err := p.limiter.Wait(context.Background()); err != nil {
+			return -1, err
+		}
+
+		hour, retryable, err := p.doRequest(data)
+		if err == nil {
+			return hour, nil
+		}
+		lastErr = err
+		if !retryable {
+			return -1, err
+		}
+	}
+
+	return -1, fmt.Errorf("after %d attempts: %w", p.maxRetries+1, lastErr)
+}
+
+// doRequest performs a single attempt at the API call, reporting whether the
+// failure (if any) is worth retrying.
+func (p *HTTPSunsetProvider) doRequest(data Structure) (hour int, retryable bool, err error) {
+	apiURL := fmt.Sprintf("https://api.sunrise-sunset.org/json?lat=%f&lng=%f&date=%s", data.Lat, data.Lng, data.Date)
+
+	resp, err := p.client.Get(apiURL)
+	if err != nil {
+		return -1, true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return -1, true, fmt.Errorf("api returned HTTP %d", resp.StatusCode)
+	}
+
+	var result apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return -1, false, err
+	}
+
+	if result.Status != "OK" {
+		apiErrorsTotal.WithLabelValues(result.Status).Inc()
+		return -1, result.Status == "UNKNOWN_ERROR", fmt.Errorf("api returned status: %s", result.Status)
+	}
+
+	sunsetTime, err := time.Parse("3:04:05 PM", result.Results.Sunset)
+	if err != nil {
+		return -1, false, err
+	}
+
+	return sunsetTime.Hour(), false, nil
+}
+
+// backoffWithJitter returns base doubled attempt-1 times, plus up to half of
+// that as random jitter, to avoid workers retrying in lockstep.
+func backoffWithJitter(base time.Duration, attempt int) time.Duration {
+	backoff := base * time.Duration(1<<uint(attempt-1))
+	jitter := time.Duration(rand.Int63n(int64(backoff)/2 + 1))
+	return backoff + jitter
+}
+
+type apiResponse struct {
+	Results struct {
+		Sunset string `json:"sunset"`
+	} `json:"results"`
+	Status string `json:"status"`
+}
+
+// ------------------------------------------------------- Local Provider
+
+// LocalSunsetProvider computes the sunset hour offline using the NOAA Solar
+// Calculator formulas, removing the network dependency entirely.
+type LocalSunsetProvider struct{}
+
+// NewLocalSunsetProvider creates a LocalSunsetProvider.
+func NewLocalSunsetProvider() *LocalSunsetProvider {
+	return &LocalSunsetProvider{}
+}
+
+// FindSunsetHour computes the local sunset hour for data.Lat/data.Lng on
+// data.Date, using the NOAA Solar Calculator formulas.
+func (p *LocalSunsetProvider) FindSunsetHour(data Structure) (int, error) {
+	date, err := time.Parse("2006-01-02", data.Date)
+	if err != nil {
+		return -1, err
+	}
+
+	jd := julianDay(date)
+	jc := (jd - 2451545.0) / 36525.0
+
+	geomMeanLongSun := math.Mod(280.46646+jc*(36000.76983+jc*0.0003032), 360)
+	geomMeanAnomSun := 357.52911 + jc*(35999.05029-0.0001537*jc)
+	eccentEarthOrbit := 0.016708634 - jc*(0.000042037+0.0000001267*jc)
+
+	sunEqOfCtr := math.Sin(radians(geomMeanAnomSun))*(1.914602-jc*(0.004817+0.000014*jc)) +
+		math.Sin(radians(2*geomMeanAnomSun))*(0.019993-0.000101*jc) +
+		math.Sin(radians(3*geomMeanAnomSun))*0.000289
+
+	sunTrueLong := geomMeanLongSun + sunEqOfCtr
+	sunAppLong := sunTrueLong - 0.00569 - 0.00478*math.Sin(radians(125.04-1934.136*jc))
+
+	meanObliqEcliptic := 23 + (26+(21.448-jc*(46.815+jc*(0.00059-jc*0.001813)))/60)/60
+	obliqCorr := meanObliqEcliptic + 0.00256*math.Cos(radians(125.04-1934.136*jc))
+
+	sunDeclin := degrees(math.Asin(math.Sin(radians(obliqCorr)) * math.Sin(radians(sunAppLong))))
+
+	varY := math.Tan(radians(obliqCorr/2)) * math.Tan(radians(obliqCorr/2))
+	eqTime := 4 * degrees(
+		varY*math.Sin(2*radians(geomMeanLongSun))-
+			2*eccentEarthOrbit*math.Sin(radians(geomMeanAnomSun))+
+			4*eccentEarthOrbit*varY*math.Sin(radians(geomMeanAnomSun))*math.Cos(2*radians(geomMeanLongSun))-
+			0.5*varY*varY*math.Sin(4*radians(geomMeanLongSun))-
+			1.25*eccentEarthOrbit*eccentEarthOrbit*math.Sin(2*radians(geomMeanAnomSun)))
+
+	latRad := radians(data.Lat)
+	declRad := radians(sunDeclin)
+
+	cosHourAngle := math.Cos(radians(90.833))/(math.Cos(latRad)*math.Cos(declRad)) - math.Tan(latRad)*math.Tan(declRad)
+	if cosHourAngle < -1 || cosHourAngle > 1 {
+		return -1, fmt.Errorf("no sunset at lat=%.4f on %s (polar day/night)", data.Lat, data.Date)
+	}
+	hourAngle := math.Acos(cosHourAngle)
+
+	sunsetUTCMinutes := 720 - 4*data.Lng + 4*degrees(hourAngle) - eqTime
+	minutes := math.Mod(sunsetUTCMinutes, 1440)
+	if minutes < 0 {
+		minutes += 1440
+	}
+
+	return int(minutes / 60), nil
+}
+
+// julianDay returns the Julian day number for date at 0h UTC.
+func julianDay(date time.Time) float64 {
+	year, month, day := date.Year(), int(date.Month()), date.Day()
+	if month <= 2 {
+		year--
+		month += 12
+	}
+	a := year / 100
+	b := 2 - a + a/4
+	return math.Floor(365.25*float64(year+4716)) + math.Floor(30.6001*float64(month+1)) + float64(day) + float64(b) - 1524.5
+}
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+func degrees(rad float64) float64 { return rad * 180 / math.Pi }
+
+// ------------------------------------------------------- Caching Decorator
+
+// CachingSunsetProvider wraps another SunsetProvider and shares results
+// between jobs with nearby coordinates, keyed by (round(lat,2), round(lng,2),
+// date).
+type CachingSunsetProvider struct {
+	provider SunsetProvider
+
+	mu    sync.Mutex
+	cache map[string]int
+}
+
+// NewCachingSunsetProvider creates a CachingSunsetProvider wrapping provider.
+func NewCachingSunsetProvider(provider SunsetProvider) *CachingSunsetProvider {
+	return &CachingSunsetProvider{
+		provider: provider,
+		cache:    make(map[string]int),
+	}
+}
+
+// FindSunsetHour returns the cached sunset hour for data's rounded coordinates
+// and date, computing and caching it via the wrapped provider on a miss.
+func (p *CachingSunsetProvider) FindSunsetHour(data Structure) (int, error) {
+	key := cacheKey(data.Lat, data.Lng, data.Date)
+
+	p.mu.Lock()
+	if hour, ok := p.cache[key]; ok {
+		p.mu.Unlock()
+		return hour, nil
+	}
+	p.mu.Unlock()
+
+	hour, err := p.provider.FindSunsetHour(data)
+	if err != nil {
+		return -1, err
+	}
+
+	p.mu.Lock()
+	p.cache[key] = hour
+	p.mu.Unlock()
+
+	return hour, nil
+}
+
+func cacheKey(lat, lng float64, date string) string {
+	round := func(v float64) float64 { return math.Round(v*100) / 100 }
+	return fmt.Sprintf("%.2f|%.2f|%s", round(lat), round(lng), date)
+}
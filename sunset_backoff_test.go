@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestBackoffWithJitterGrowsAndStaysBounded guards the retry/backoff math in
+// HTTPSunsetProvider.FindSunsetHour: each attempt's backoff must be at least
+// the doubled base (no jitter shrinks it) and at most 1.5x the doubled base
+// (jitter adds at most half of it), so workers back off instead of hammering
+// the API, without the deterministic growth breaking down.
+func TestBackoffWithJitterGrowsAndStaysBounded(t *testing.T) {
+	const base = 100 * time.Millisecond
+
+	for attempt := 1; attempt <= 5; attempt++ {
+		want := base * time.Duration(1<<uint(attempt-1))
+		for i := 0; i < 20; i++ {
+			got := backoffWithJitter(base, attempt)
+			if got < want {
+				t.Fatalf("attempt %d: backoffWithJitter = %v, want >= %v", attempt, got, want)
+			}
+			if got > want+want/2 {
+				t.Fatalf("attempt %d: backoffWithJitter = %v, want <= %v", attempt, got, want+want/2)
+			}
+		}
+	}
+}
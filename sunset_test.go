@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+// TestLocalSunsetProviderEquatorEquinox guards against sign errors in the
+// NOAA formula (e.g. a sunrise/sunset swap) by checking a well-known,
+// non-degenerate case: at the equator on the equinox, sunset is close to
+// 18:00 UTC.
+func TestLocalSunsetProviderEquatorEquinox(t *testing.T) {
+	provider := NewLocalSunsetProvider()
+
+	hour, err := provider.FindSunsetHour(Structure{Date: "2026-03-20", Lat: 0, Lng: 0})
+	if err != nil {
+		t.Fatalf("FindSunsetHour returned error: %v", err)
+	}
+
+	const wantHour = 18
+	if hour != wantHour {
+		t.Errorf("FindSunsetHour() = %d, want %d (equatorial equinox sunset is ~18:11 UTC)", hour, wantHour)
+	}
+}